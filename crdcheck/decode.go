@@ -0,0 +1,42 @@
+package crdcheck
+
+import (
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kjson "k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/yaml"
+)
+
+// SafeDecodeCRD decodes a CRD YAML document into a typed
+// CustomResourceDefinition without losing the distinction between integers
+// and floats along the way.
+//
+// The naive pipeline — yaml.Unmarshal into map[string]interface{}, then
+// runtime.DefaultUnstructuredConverter.FromUnstructured — round-trips
+// through encoding/json's default number handling, which decodes every
+// number as float64. A schema default of `0` under `format: int64`, or an
+// enum of `[0, 1, 2]`, comes out the other side as `0.0`/`1.0`/`2.0`,
+// silently corrupting the CRD. SafeDecodeCRD instead converts the YAML to
+// JSON and decodes it with k8s.io/apimachinery's UseNumber-aware decoder,
+// which turns whole numbers into int64 (and only non-integral numbers into
+// float64), so the unstructured converter writes them back out as integers.
+func SafeDecodeCRD(data []byte) (*apiextensionsv1.CustomResourceDefinition, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("crdcheck: converting yaml to json: %w", err)
+	}
+
+	var obj map[string]interface{}
+	if err := kjson.Unmarshal(jsonData, &obj); err != nil {
+		return nil, fmt.Errorf("crdcheck: decoding CRD json: %w", err)
+	}
+
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, &crd); err != nil {
+		return nil, fmt.Errorf("crdcheck: converting unstructured object: %w", err)
+	}
+
+	return &crd, nil
+}
@@ -0,0 +1,79 @@
+package crdcheck
+
+import "testing"
+
+func TestSafeDecodeCRDPreservesIntegerDefault(t *testing.T) {
+	const doc = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    listKind: WidgetList
+    plural: widgets
+    singular: widget
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              replicas:
+                type: integer
+                format: int64
+                default: 0
+              mode:
+                type: integer
+                enum: [0, 1, 2]
+              limits:
+                type: object
+                additionalProperties:
+                  type: integer
+`
+
+	crd, err := SafeDecodeCRD([]byte(doc))
+	if err != nil {
+		t.Fatalf("SafeDecodeCRD returned error: %v", err)
+	}
+
+	spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+
+	replicas := spec.Properties["replicas"]
+	if got := string(replicas.Default.Raw); got != "0" {
+		t.Errorf("replicas default = %q, want %q (no float coercion)", got, "0")
+	}
+
+	mode := spec.Properties["mode"]
+	if len(mode.Enum) != 3 {
+		t.Fatalf("mode enum length = %d, want 3", len(mode.Enum))
+	}
+	for i, want := range []string{"0", "1", "2"} {
+		if got := string(mode.Enum[i].Raw); got != want {
+			t.Errorf("mode enum[%d] = %q, want %q", i, got, want)
+		}
+	}
+
+	limits := spec.Properties["limits"]
+	if limits.AdditionalProperties == nil || limits.AdditionalProperties.Schema == nil {
+		t.Fatalf("limits.additionalProperties schema missing")
+	}
+	if limits.AdditionalProperties.Schema.Type != "integer" {
+		t.Errorf("limits.additionalProperties.type = %q, want %q", limits.AdditionalProperties.Schema.Type, "integer")
+	}
+}
+
+func TestSafeDecodeCRDInvalidYAML(t *testing.T) {
+	_, err := SafeDecodeCRD([]byte("not: valid: yaml: :"))
+	if err == nil {
+		t.Fatal("expected an error for invalid yaml, got nil")
+	}
+}
@@ -0,0 +1,361 @@
+// Package crdcheck performs structural diffing between the CRD a controller
+// was compiled against ("expected") and the CRD actually installed in a
+// cluster ("actual"), producing a DriftReport instead of printing findings
+// to stdout the way the original checkCRD/compareSchemaProperties helpers
+// in package main did.
+package crdcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Severity classifies how a controller should react to a piece of drift.
+type Severity int
+
+const (
+	// SeverityInfo is drift that is safe to ignore, e.g. an added optional
+	// field the controller doesn't know about yet.
+	SeverityInfo Severity = iota
+	// SeverityWarning is drift a controller should log but can tolerate,
+	// such as a weakened (but not removed) validation constraint.
+	SeverityWarning
+	// SeverityFatal is drift that means the controller must refuse to
+	// start, such as a missing required field it depends on.
+	SeverityFatal
+)
+
+// String implements fmt.Stringer for log-friendly output.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "Info"
+	case SeverityWarning:
+		return "Warning"
+	case SeverityFatal:
+		return "Fatal"
+	default:
+		return "Unknown"
+	}
+}
+
+// DriftKind identifies the category of a single Finding.
+type DriftKind int
+
+const (
+	KindMissingField DriftKind = iota
+	KindTypeMismatch
+	KindWeakenedValidation
+	KindRemovedEnumValue
+	KindChangedDefault
+)
+
+// Finding describes a single piece of drift found at Path between the
+// expected and actual schema.
+type Finding struct {
+	Version  string
+	Path     string
+	Kind     DriftKind
+	Severity Severity
+	Expected interface{}
+	Actual   interface{}
+	Message  string
+}
+
+// DriftReport is the structured result of diffing an expected CRD against
+// the actual CRD found in the cluster, across every version declared in
+// spec.versions.
+type DriftReport struct {
+	Name     string
+	Findings []Finding
+}
+
+// HasDrift reports whether any findings were recorded.
+func (r *DriftReport) HasDrift() bool {
+	return len(r.Findings) > 0
+}
+
+// WorstSeverity returns the highest Severity across all findings, or
+// SeverityInfo if the report is empty.
+func (r *DriftReport) WorstSeverity() Severity {
+	worst := SeverityInfo
+	for _, f := range r.Findings {
+		if f.Severity > worst {
+			worst = f.Severity
+		}
+	}
+	return worst
+}
+
+func (r *DriftReport) add(f Finding) {
+	r.Findings = append(r.Findings, f)
+}
+
+// Diff performs a full recursive diff between expected and actual across
+// every version present in both CRDs' spec.versions. Versions present only
+// in actual are reported as informational; versions present only in
+// expected are reported as fatal, since the controller has no schema to
+// fall back on.
+func Diff(expected, actual *apiextensionsv1.CustomResourceDefinition) *DriftReport {
+	report := &DriftReport{Name: expected.Name}
+
+	actualVersions := make(map[string]*apiextensionsv1.CustomResourceValidation, len(actual.Spec.Versions))
+	for _, v := range actual.Spec.Versions {
+		actualVersions[v.Name] = v.Schema
+	}
+
+	for _, ev := range expected.Spec.Versions {
+		av, ok := actualVersions[ev.Name]
+		if !ok {
+			report.add(Finding{
+				Version:  ev.Name,
+				Path:     "$",
+				Kind:     KindMissingField,
+				Severity: SeverityFatal,
+				Message:  fmt.Sprintf("version %q is declared by the controller but absent from the cluster CRD", ev.Name),
+			})
+			continue
+		}
+		diffSchema(report, ev.Name, "$", ev.Schema.OpenAPIV3Schema, av.OpenAPIV3Schema)
+		delete(actualVersions, ev.Name)
+	}
+
+	for name := range actualVersions {
+		report.add(Finding{
+			Version:  name,
+			Path:     "$",
+			Kind:     KindMissingField,
+			Severity: SeverityInfo,
+			Message:  fmt.Sprintf("version %q exists in the cluster but the controller was not compiled against it", name),
+		})
+	}
+
+	return report
+}
+
+// diffSchema recursively compares an expected and actual JSONSchemaProps
+// node, recording findings against report rooted at path.
+func diffSchema(report *DriftReport, version, path string, expected, actual *apiextensionsv1.JSONSchemaProps) {
+	if expected == nil {
+		return
+	}
+	if actual == nil {
+		report.add(Finding{
+			Version: version, Path: path, Kind: KindMissingField, Severity: SeverityFatal,
+			Message: fmt.Sprintf("%s is missing from the actual CRD", path),
+		})
+		return
+	}
+
+	if expected.Type != "" && expected.Type != actual.Type {
+		report.add(Finding{
+			Version: version, Path: path, Kind: KindTypeMismatch, Severity: SeverityFatal,
+			Expected: expected.Type, Actual: actual.Type,
+			Message: fmt.Sprintf("%s type changed from %q to %q", path, expected.Type, actual.Type),
+		})
+	}
+
+	if expected.Format != actual.Format {
+		report.add(Finding{
+			Version: version, Path: path, Kind: KindTypeMismatch, Severity: SeverityWarning,
+			Expected: expected.Format, Actual: actual.Format,
+			Message: fmt.Sprintf("%s format changed from %q to %q", path, expected.Format, actual.Format),
+		})
+	}
+
+	diffExtensions(report, version, path, expected, actual)
+	diffRequired(report, version, path, expected, actual)
+	diffEnum(report, version, path, expected, actual)
+	diffDefault(report, version, path, expected, actual)
+
+	for name, expectedProp := range expected.Properties {
+		actualProp, ok := actual.Properties[name]
+		childPath := path + "." + name
+		if !ok {
+			report.add(Finding{
+				Version: version, Path: childPath, Kind: KindMissingField, Severity: SeverityFatal,
+				Message: fmt.Sprintf("%s is missing from the actual CRD", childPath),
+			})
+			continue
+		}
+		diffSchema(report, version, childPath, &expectedProp, &actualProp)
+	}
+
+	if expected.Items != nil && expected.Items.Schema != nil {
+		itemsPath := path + "[]"
+		if actual.Items == nil || actual.Items.Schema == nil {
+			report.add(Finding{
+				Version: version, Path: itemsPath, Kind: KindMissingField, Severity: SeverityFatal,
+				Message: fmt.Sprintf("%s is missing an items schema in the actual CRD", itemsPath),
+			})
+		} else {
+			diffSchema(report, version, itemsPath, expected.Items.Schema, actual.Items.Schema)
+		}
+	}
+
+	diffAdditionalProperties(report, version, path, expected, actual)
+	diffComposition(report, version, path, "oneOf", expected.OneOf, actual.OneOf)
+	diffComposition(report, version, path, "anyOf", expected.AnyOf, actual.AnyOf)
+	diffComposition(report, version, path, "allOf", expected.AllOf, actual.AllOf)
+
+	if expected.XPreserveUnknownFields != nil && !boolPtrEqual(expected.XPreserveUnknownFields, actual.XPreserveUnknownFields) {
+		report.add(Finding{
+			Version: version, Path: path, Kind: KindWeakenedValidation, Severity: SeverityWarning,
+			Message: fmt.Sprintf("%s preserveUnknownFields changed", path),
+		})
+	}
+}
+
+func diffRequired(report *DriftReport, version, path string, expected, actual *apiextensionsv1.JSONSchemaProps) {
+	actualRequired := make(map[string]bool, len(actual.Required))
+	for _, r := range actual.Required {
+		actualRequired[r] = true
+	}
+	for _, r := range expected.Required {
+		if !actualRequired[r] {
+			report.add(Finding{
+				Version: version, Path: path + "." + r, Kind: KindWeakenedValidation, Severity: SeverityFatal,
+				Message: fmt.Sprintf("%s.%s is no longer required", path, r),
+			})
+		}
+	}
+}
+
+func diffEnum(report *DriftReport, version, path string, expected, actual *apiextensionsv1.JSONSchemaProps) {
+	if len(expected.Enum) == 0 {
+		return
+	}
+	actualValues := make(map[string]bool, len(actual.Enum))
+	for _, v := range actual.Enum {
+		actualValues[string(v.Raw)] = true
+	}
+	for _, v := range expected.Enum {
+		if !actualValues[string(v.Raw)] {
+			report.add(Finding{
+				Version: version, Path: path, Kind: KindRemovedEnumValue, Severity: SeverityWarning,
+				Expected: string(v.Raw),
+				Message:  fmt.Sprintf("%s enum value %s was removed", path, string(v.Raw)),
+			})
+		}
+	}
+}
+
+func diffDefault(report *DriftReport, version, path string, expected, actual *apiextensionsv1.JSONSchemaProps) {
+	if expected.Default == nil {
+		return
+	}
+	expectedRaw := string(expected.Default.Raw)
+	var actualRaw string
+	if actual.Default != nil {
+		actualRaw = string(actual.Default.Raw)
+	}
+	if expectedRaw != actualRaw {
+		report.add(Finding{
+			Version: version, Path: path, Kind: KindChangedDefault, Severity: SeverityWarning,
+			Expected: expectedRaw, Actual: actualRaw,
+			Message: fmt.Sprintf("%s default changed from %s to %s", path, expectedRaw, actualRaw),
+		})
+	}
+}
+
+func diffAdditionalProperties(report *DriftReport, version, path string, expected, actual *apiextensionsv1.JSONSchemaProps) {
+	if expected.AdditionalProperties == nil || expected.AdditionalProperties.Schema == nil {
+		return
+	}
+	if actual.AdditionalProperties == nil || actual.AdditionalProperties.Schema == nil {
+		report.add(Finding{
+			Version: version, Path: path + ".additionalProperties", Kind: KindMissingField, Severity: SeverityWarning,
+			Message: fmt.Sprintf("%s.additionalProperties schema was dropped", path),
+		})
+		return
+	}
+	diffSchema(report, version, path+".additionalProperties", expected.AdditionalProperties.Schema, actual.AdditionalProperties.Schema)
+}
+
+func diffComposition(report *DriftReport, version, path, label string, expected, actual []apiextensionsv1.JSONSchemaProps) {
+	if len(expected) > len(actual) {
+		report.add(Finding{
+			Version: version, Path: path + "." + label, Kind: KindWeakenedValidation, Severity: SeverityWarning,
+			Message: fmt.Sprintf("%s.%s lost %d branch(es)", path, label, len(expected)-len(actual)),
+		})
+	}
+}
+
+func diffExtensions(report *DriftReport, version, path string, expected, actual *apiextensionsv1.JSONSchemaProps) {
+	if expected.XIntOrString != actual.XIntOrString {
+		report.add(Finding{
+			Version: version, Path: path, Kind: KindWeakenedValidation, Severity: SeverityWarning,
+			Message: fmt.Sprintf("%s x-kubernetes-int-or-string changed from %v to %v", path, expected.XIntOrString, actual.XIntOrString),
+		})
+	}
+	if len(expected.XValidations) > len(actual.XValidations) {
+		report.add(Finding{
+			Version: version, Path: path, Kind: KindWeakenedValidation, Severity: SeverityWarning,
+			Message: fmt.Sprintf("%s lost one or more x-kubernetes-validations rules", path),
+		})
+	}
+}
+
+func boolPtrEqual(a, b *bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// CRDClient is the subset of a generated apiextensionsv1 clientset's
+// CustomResourceDefinitionInterface that Apply needs. Since CRDs are
+// cluster-scoped, this matches that interface's Patch method signature
+// exactly, so the real clientset's CustomResourceDefinitionInterface
+// satisfies CRDClient with no adapter needed.
+type CRDClient interface {
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*apiextensionsv1.CustomResourceDefinition, error)
+}
+
+// ApplyMode controls how Apply reconciles a DriftReport.
+type ApplyMode int
+
+const (
+	// ApplyModeReportOnly never mutates the cluster.
+	ApplyModeReportOnly ApplyMode = iota
+	// ApplyModeSelfHeal patches the cluster CRD to match expected for any
+	// non-fatal finding.
+	ApplyModeSelfHeal
+)
+
+// Apply optionally reconciles drift described by report by PATCHing the CRD
+// back to the expected shape, mirroring how operator init flows verify and
+// then upgrade their own CRDs. It refuses to act when mode is
+// ApplyModeReportOnly or when the report contains a SeverityFatal finding,
+// since self-healing over a fatal mismatch could mask a real incompatibility.
+func Apply(ctx context.Context, client CRDClient, expected *apiextensionsv1.CustomResourceDefinition, report *DriftReport, mode ApplyMode) error {
+	if mode == ApplyModeReportOnly {
+		return nil
+	}
+	if report.WorstSeverity() == SeverityFatal {
+		return fmt.Errorf("crdcheck: refusing to apply drift for %q: fatal finding present", report.Name)
+	}
+	if !report.HasDrift() {
+		return nil
+	}
+
+	patch, err := expectedAsMergePatch(expected)
+	if err != nil {
+		return fmt.Errorf("crdcheck: building merge patch for %q: %w", report.Name, err)
+	}
+
+	_, err = client.Patch(ctx, expected.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// expectedAsMergePatch encodes expected.Spec as a JSON merge patch body.
+func expectedAsMergePatch(expected *apiextensionsv1.CustomResourceDefinition) ([]byte, error) {
+	return json.Marshal(struct {
+		Spec apiextensionsv1.CustomResourceDefinitionSpec `json:"spec"`
+	}{Spec: expected.Spec})
+}
@@ -0,0 +1,280 @@
+package crdcheck
+
+import (
+	"context"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func mustDecode(t *testing.T, doc string) *apiextensionsv1.CustomResourceDefinition {
+	t.Helper()
+	crd, err := SafeDecodeCRD([]byte(doc))
+	if err != nil {
+		t.Fatalf("SafeDecodeCRD returned error: %v", err)
+	}
+	return crd
+}
+
+const expectedWidgetCRD = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    listKind: WidgetList
+    plural: widgets
+    singular: widget
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            required: ["size"]
+            properties:
+              size:
+                type: integer
+                format: int64
+                default: 1
+              mode:
+                type: string
+                enum: ["a", "b", "c"]
+              tags:
+                type: array
+                items:
+                  type: string
+`
+
+func TestDiffFindsMissingRequiredProperty(t *testing.T) {
+	expected := mustDecode(t, expectedWidgetCRD)
+	actual := mustDecode(t, `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    listKind: WidgetList
+    plural: widgets
+    singular: widget
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              mode:
+                type: string
+`)
+
+	report := Diff(expected, actual)
+
+	if !report.HasDrift() {
+		t.Fatal("expected drift, got none")
+	}
+	if report.WorstSeverity() != SeverityFatal {
+		t.Errorf("WorstSeverity() = %v, want %v", report.WorstSeverity(), SeverityFatal)
+	}
+
+	var foundSize, foundTags bool
+	for _, f := range report.Findings {
+		if f.Path == "$.spec.size" {
+			foundSize = true
+		}
+		if f.Path == "$.spec.tags" {
+			foundTags = true
+		}
+	}
+	if !foundSize {
+		t.Errorf("expected a finding for missing $.spec.size, findings: %+v", report.Findings)
+	}
+	if !foundTags {
+		t.Errorf("expected a finding for missing $.spec.tags, findings: %+v", report.Findings)
+	}
+}
+
+func TestDiffFindsWeakenedEnumAndDefault(t *testing.T) {
+	expected := mustDecode(t, expectedWidgetCRD)
+	actual := mustDecode(t, `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    listKind: WidgetList
+    plural: widgets
+    singular: widget
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            required: ["size"]
+            properties:
+              size:
+                type: integer
+                format: int64
+                default: 0
+              mode:
+                type: string
+                enum: ["a", "b"]
+              tags:
+                type: array
+                items:
+                  type: string
+`)
+
+	report := Diff(expected, actual)
+	if !report.HasDrift() {
+		t.Fatal("expected drift, got none")
+	}
+
+	var foundDefault, foundEnum bool
+	for _, f := range report.Findings {
+		if f.Path == "$.spec.size" && f.Kind == KindChangedDefault {
+			foundDefault = true
+		}
+		if f.Path == "$.spec.mode" && f.Kind == KindRemovedEnumValue {
+			foundEnum = true
+		}
+	}
+	if !foundDefault {
+		t.Errorf("expected a KindChangedDefault finding for $.spec.size, findings: %+v", report.Findings)
+	}
+	if !foundEnum {
+		t.Errorf("expected a KindRemovedEnumValue finding for $.spec.mode, findings: %+v", report.Findings)
+	}
+	if report.WorstSeverity() == SeverityFatal {
+		t.Errorf("WorstSeverity() = %v, want non-fatal (only warnings introduced)", report.WorstSeverity())
+	}
+}
+
+func TestDiffIdenticalSchemaHasNoDrift(t *testing.T) {
+	expected := mustDecode(t, expectedWidgetCRD)
+	actual := mustDecode(t, expectedWidgetCRD)
+
+	report := Diff(expected, actual)
+	if report.HasDrift() {
+		t.Errorf("expected no drift between identical schemas, got: %+v", report.Findings)
+	}
+}
+
+func TestDiffMissingVersionIsFatal(t *testing.T) {
+	expected := mustDecode(t, expectedWidgetCRD)
+	actual := mustDecode(t, `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    listKind: WidgetList
+    plural: widgets
+    singular: widget
+  scope: Namespaced
+  versions:
+  - name: v2
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+`)
+
+	report := Diff(expected, actual)
+	if report.WorstSeverity() != SeverityFatal {
+		t.Errorf("WorstSeverity() = %v, want %v when the compiled-against version is entirely absent", report.WorstSeverity(), SeverityFatal)
+	}
+}
+
+// fakeCRDClient is a minimal CRDClient used to exercise Apply without a real
+// cluster.
+type fakeCRDClient struct {
+	patchedName string
+	patchedData []byte
+	err         error
+}
+
+func (f *fakeCRDClient) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	f.patchedName = name
+	f.patchedData = data
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &apiextensionsv1.CustomResourceDefinition{}, nil
+}
+
+var _ CRDClient = (*fakeCRDClient)(nil)
+
+func TestApplyReportOnlyNeverPatches(t *testing.T) {
+	expected := mustDecode(t, expectedWidgetCRD)
+	report := &DriftReport{Name: expected.Name, Findings: []Finding{{Severity: SeverityWarning}}}
+
+	client := &fakeCRDClient{}
+	if err := Apply(context.Background(), client, expected, report, ApplyModeReportOnly); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	if client.patchedName != "" {
+		t.Errorf("expected no patch to be issued in ApplyModeReportOnly, got patch for %q", client.patchedName)
+	}
+}
+
+func TestApplyRefusesFatalDrift(t *testing.T) {
+	expected := mustDecode(t, expectedWidgetCRD)
+	report := &DriftReport{Name: expected.Name, Findings: []Finding{{Severity: SeverityFatal}}}
+
+	client := &fakeCRDClient{}
+	err := Apply(context.Background(), client, expected, report, ApplyModeSelfHeal)
+	if err == nil {
+		t.Fatal("expected Apply to refuse fatal drift, got nil error")
+	}
+	if client.patchedName != "" {
+		t.Errorf("expected no patch to be issued for fatal drift, got patch for %q", client.patchedName)
+	}
+}
+
+func TestApplySelfHealPatchesOnNonFatalDrift(t *testing.T) {
+	expected := mustDecode(t, expectedWidgetCRD)
+	report := &DriftReport{Name: expected.Name, Findings: []Finding{{Severity: SeverityWarning}}}
+
+	client := &fakeCRDClient{}
+	if err := Apply(context.Background(), client, expected, report, ApplyModeSelfHeal); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	if client.patchedName != expected.Name {
+		t.Errorf("patchedName = %q, want %q", client.patchedName, expected.Name)
+	}
+	if len(client.patchedData) == 0 {
+		t.Error("expected non-empty patch data")
+	}
+}
@@ -0,0 +1,370 @@
+package crdcheck
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// SampleMode controls how much of a schema GenerateSample fills in.
+type SampleMode int
+
+const (
+	// SampleRequiredOnly populates only fields listed in `required`.
+	SampleRequiredOnly SampleMode = iota
+	// SampleAllFields populates every declared property, required or not.
+	SampleAllFields
+	// SampleWithComments behaves like SampleAllFields but GenerateSampleYAML
+	// also emits each field's description as a trailing `# description: ...`
+	// comment.
+	SampleWithComments
+)
+
+// SampleOptions configures GenerateSample.
+type SampleOptions struct {
+	Mode SampleMode
+}
+
+// GenerateSample walks the OpenAPIV3Schema for the given version of crd and
+// emits a minimal-but-valid example custom resource: required fields
+// populated with type-appropriate example values, enum fields set to the
+// first allowed value, default honored where present, format (int64,
+// date-time, byte) respected, and x-kubernetes-int-or-string handled by
+// preferring the string form.
+func GenerateSample(crd *apiextensionsv1.CustomResourceDefinition, version string, opts SampleOptions) (*unstructured.Unstructured, error) {
+	schema, err := schemaForVersion(crd, version)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := sampleValue(schema, opts.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("crdcheck: generating sample for %s/%s: %w", crd.Name, version, err)
+	}
+
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("crdcheck: root schema for %s/%s did not produce an object", crd.Name, version)
+	}
+	m["apiVersion"] = crd.Spec.Group + "/" + version
+	m["kind"] = crd.Spec.Names.Kind
+	meta, _ := m["metadata"].(map[string]interface{})
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	if _, ok := meta["name"]; !ok {
+		meta["name"] = fmt.Sprintf("example-%s", crd.Spec.Names.Singular)
+	}
+	m["metadata"] = meta
+
+	return &unstructured.Unstructured{Object: m}, nil
+}
+
+// GenerateSampleYAML is a convenience wrapper around GenerateSample that
+// marshals the result to YAML. When opts.Mode is SampleWithComments, each
+// field with a non-empty Description is annotated with a trailing
+// `# description: ...` comment.
+func GenerateSampleYAML(crd *apiextensionsv1.CustomResourceDefinition, version string, opts SampleOptions) ([]byte, error) {
+	sample, err := GenerateSample(crd, version, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := yaml.Marshal(sample.Object)
+	if err != nil {
+		return nil, fmt.Errorf("crdcheck: marshalling sample to yaml: %w", err)
+	}
+	if opts.Mode != SampleWithComments {
+		return out, nil
+	}
+
+	schema, err := schemaForVersion(crd, version)
+	if err != nil {
+		return out, nil
+	}
+	return annotateWithDescriptions(out, schema), nil
+}
+
+func schemaForVersion(crd *apiextensionsv1.CustomResourceDefinition, version string) (*apiextensionsv1.JSONSchemaProps, error) {
+	for _, v := range crd.Spec.Versions {
+		if v.Name == version && v.Schema != nil {
+			return v.Schema.OpenAPIV3Schema, nil
+		}
+	}
+	return nil, fmt.Errorf("crdcheck: version %q not found in CRD %s", version, crd.Name)
+}
+
+func sampleValue(schema *apiextensionsv1.JSONSchemaProps, mode SampleMode) (interface{}, error) {
+	if schema == nil {
+		return nil, nil
+	}
+
+	if schema.Default != nil {
+		var v interface{}
+		if err := yaml.Unmarshal(schema.Default.Raw, &v); err == nil {
+			return v, nil
+		}
+	}
+
+	if len(schema.Enum) > 0 {
+		var v interface{}
+		if err := yaml.Unmarshal(schema.Enum[0].Raw, &v); err == nil {
+			return v, nil
+		}
+	}
+
+	if schema.XIntOrString {
+		return "0", nil
+	}
+
+	switch schema.Type {
+	case "object":
+		return sampleObject(schema, mode)
+	case "array":
+		return sampleArray(schema, mode)
+	case "string":
+		return sampleString(schema), nil
+	case "integer":
+		return sampleInteger(schema), nil
+	case "number":
+		return 0.0, nil
+	case "boolean":
+		return false, nil
+	default:
+		return nil, nil
+	}
+}
+
+func sampleObject(schema *apiextensionsv1.JSONSchemaProps, mode SampleMode) (interface{}, error) {
+	result := map[string]interface{}{}
+
+	required := make(map[string]bool, len(schema.Required))
+	for _, r := range schema.Required {
+		required[r] = true
+	}
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop := schema.Properties[name]
+		// In SampleRequiredOnly mode, a property is still included if it
+		// isn't itself required but some descendant of it is (e.g. `spec`
+		// is rarely required at the CRD's root, yet `spec.size` usually
+		// is) — otherwise the required descendant could never be reached.
+		if mode == SampleRequiredOnly && !required[name] && !hasRequiredDescendant(&prop) {
+			continue
+		}
+		v, err := sampleValue(&prop, mode)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = v
+	}
+
+	if len(result) == 0 && schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		v, err := sampleValue(schema.AdditionalProperties.Schema, mode)
+		if err != nil {
+			return nil, err
+		}
+		result["example"] = v
+	}
+
+	return result, nil
+}
+
+// hasRequiredDescendant reports whether schema itself declares any required
+// properties, or any of its properties/items does, at any depth.
+func hasRequiredDescendant(schema *apiextensionsv1.JSONSchemaProps) bool {
+	if schema == nil {
+		return false
+	}
+	if len(schema.Required) > 0 {
+		return true
+	}
+	for _, prop := range schema.Properties {
+		if hasRequiredDescendant(&prop) {
+			return true
+		}
+	}
+	if schema.Items != nil && schema.Items.Schema != nil {
+		return hasRequiredDescendant(schema.Items.Schema)
+	}
+	return false
+}
+
+func sampleArray(schema *apiextensionsv1.JSONSchemaProps, mode SampleMode) (interface{}, error) {
+	if schema.Items == nil || schema.Items.Schema == nil {
+		return []interface{}{}, nil
+	}
+	v, err := sampleValue(schema.Items.Schema, mode)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{v}, nil
+}
+
+func sampleString(schema *apiextensionsv1.JSONSchemaProps) interface{} {
+	switch schema.Format {
+	case "date-time":
+		return time.Unix(0, 0).UTC().Format(time.RFC3339)
+	case "date":
+		return time.Unix(0, 0).UTC().Format("2006-01-02")
+	case "byte":
+		return base64.StdEncoding.EncodeToString([]byte("example"))
+	default:
+		return "example"
+	}
+}
+
+func sampleInteger(schema *apiextensionsv1.JSONSchemaProps) interface{} {
+	if schema.Minimum != nil {
+		return int64(*schema.Minimum)
+	}
+	return int64(0)
+}
+
+// pathFrame tracks one level of the dotted path built up while walking the
+// marshalled YAML line by line, keyed by the indentation at which that
+// level's key appeared.
+type pathFrame struct {
+	indent int
+	key    string
+}
+
+// annotateWithDescriptions appends a trailing `# description: ...` comment
+// to each line whose key, resolved to its full dotted path from the YAML's
+// indentation nesting, has a non-empty Description in schema. Matching by
+// full path (rather than bare key) avoids mis-annotating a line when two
+// properties share a name at different nesting depths.
+func annotateWithDescriptions(yamlDoc []byte, schema *apiextensionsv1.JSONSchemaProps) []byte {
+	descriptions := collectDescriptions(schema, "")
+	if len(descriptions) == 0 {
+		return yamlDoc
+	}
+
+	lines := splitLines(yamlDoc)
+	var stack []pathFrame
+	for i, line := range lines {
+		key := leadingKey(line)
+		if key == "" {
+			continue
+		}
+		// A list item ("- name: example") is rendered at the same
+		// indentation as its parent array key, with the "- " prefix making
+		// room for its first field to sit one nesting level deeper. Treat
+		// that field (and, via the stack, its siblings on later lines) as
+		// one level deeper than the leading spaces alone suggest, so it
+		// nests under the array's path instead of becoming the array's
+		// sibling.
+		indent := indentOf(line)
+		if isListItem(line) {
+			indent += 2
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		path := joinPath(stack, key)
+		stack = append(stack, pathFrame{indent: indent, key: key})
+
+		if desc, ok := descriptions[path]; ok && desc != "" {
+			lines[i] = line + " # description: " + desc
+		}
+	}
+	return []byte(joinLines(lines))
+}
+
+func joinPath(stack []pathFrame, key string) string {
+	path := key
+	for i := len(stack) - 1; i >= 0; i-- {
+		path = stack[i].key + "." + path
+	}
+	return path
+}
+
+// collectDescriptions flattens a schema's Description fields keyed by their
+// full dotted path (e.g. "spec.size"), so that two properties sharing a
+// bare name at different nesting depths don't collide. An array property's
+// path passes through to its Items schema unchanged (e.g.
+// "spec.items.name", not "spec.items.0.name"), since the rendered YAML's
+// list items all share the one Items schema and aren't distinguished by
+// index.
+func collectDescriptions(schema *apiextensionsv1.JSONSchemaProps, prefix string) map[string]string {
+	out := map[string]string{}
+	if schema == nil {
+		return out
+	}
+	for name, prop := range schema.Properties {
+		path := prefix + name
+		if prop.Description != "" {
+			out[path] = prop.Description
+		}
+		for k, v := range collectDescriptions(&prop, path+".") {
+			out[k] = v
+		}
+	}
+	if schema.Items != nil && schema.Items.Schema != nil {
+		for k, v := range collectDescriptions(schema.Items.Schema, prefix) {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func splitLines(b []byte) []string {
+	var lines []string
+	start := 0
+	for i, c := range b {
+		if c == '\n' {
+			lines = append(lines, string(b[start:i]))
+			start = i + 1
+		}
+	}
+	if start < len(b) {
+		lines = append(lines, string(b[start:]))
+	}
+	return lines
+}
+
+func joinLines(lines []string) string {
+	out := ""
+	for _, l := range lines {
+		out += l + "\n"
+	}
+	return out
+}
+
+func indentOf(line string) int {
+	indent := 0
+	for indent < len(line) && line[indent] == ' ' {
+		indent++
+	}
+	return indent
+}
+
+func leadingKey(line string) string {
+	trimmed := line[indentOf(line):]
+	trimmed = strings.TrimPrefix(trimmed, "- ")
+	for i, c := range trimmed {
+		if c == ':' {
+			return trimmed[:i]
+		}
+	}
+	return ""
+}
+
+// isListItem reports whether line is a YAML sequence entry (e.g.
+// "- name: example"), as opposed to a plain "key: value" line.
+func isListItem(line string) bool {
+	return strings.HasPrefix(line[indentOf(line):], "- ")
+}
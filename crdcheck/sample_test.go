@@ -0,0 +1,255 @@
+package crdcheck
+
+import (
+	"strings"
+	"testing"
+)
+
+const widgetSampleCRD = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    listKind: WidgetList
+    plural: widgets
+    singular: widget
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            required: ["size"]
+            properties:
+              size:
+                type: integer
+                format: int64
+              mode:
+                type: string
+                enum: ["standard", "turbo"]
+              createdAt:
+                type: string
+                format: date-time
+              blob:
+                type: string
+                format: byte
+          status:
+            type: object
+            properties:
+              ready:
+                type: boolean
+`
+
+func TestGenerateSampleRequiredOnly(t *testing.T) {
+	crd := mustDecode(t, widgetSampleCRD)
+
+	sample, err := GenerateSample(crd, "v1", SampleOptions{Mode: SampleRequiredOnly})
+	if err != nil {
+		t.Fatalf("GenerateSample returned error: %v", err)
+	}
+
+	spec, ok := sample.Object["spec"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("spec missing or wrong type: %#v", sample.Object["spec"])
+	}
+	if _, ok := spec["size"]; !ok {
+		t.Errorf("expected required field 'size' to be populated, got: %#v", spec)
+	}
+	if _, ok := spec["mode"]; ok {
+		t.Errorf("expected optional field 'mode' to be omitted in required-only mode, got: %#v", spec)
+	}
+	if sample.Object["apiVersion"] != "example.com/v1" {
+		t.Errorf("apiVersion = %v, want %q", sample.Object["apiVersion"], "example.com/v1")
+	}
+	if sample.Object["kind"] != "Widget" {
+		t.Errorf("kind = %v, want %q", sample.Object["kind"], "Widget")
+	}
+}
+
+func TestGenerateSampleAllFieldsHonorsFormatsAndEnum(t *testing.T) {
+	crd := mustDecode(t, widgetSampleCRD)
+
+	sample, err := GenerateSample(crd, "v1", SampleOptions{Mode: SampleAllFields})
+	if err != nil {
+		t.Fatalf("GenerateSample returned error: %v", err)
+	}
+
+	spec := sample.Object["spec"].(map[string]interface{})
+
+	if _, ok := spec["size"].(int64); !ok {
+		t.Errorf("spec.size = %#v (%T), want an int64", spec["size"], spec["size"])
+	}
+	if mode := spec["mode"]; mode != "standard" {
+		t.Errorf("spec.mode = %v, want first enum value %q", mode, "standard")
+	}
+	if created, ok := spec["createdAt"].(string); !ok || !strings.Contains(created, "T") {
+		t.Errorf("spec.createdAt = %#v, want an RFC3339 date-time string", spec["createdAt"])
+	}
+	blob, ok := spec["blob"].(string)
+	if !ok {
+		t.Fatalf("spec.blob = %#v, want a base64 string", spec["blob"])
+	}
+	if blob == "" {
+		t.Error("spec.blob should not be empty")
+	}
+
+	status, ok := sample.Object["status"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("status missing or wrong type: %#v", sample.Object["status"])
+	}
+	if _, ok := status["ready"].(bool); !ok {
+		t.Errorf("status.ready = %#v, want a bool", status["ready"])
+	}
+}
+
+// indexOfKeyBlock returns the byte offset of the "\n" preceding the first
+// line in text whose trimmed key equals key, or -1 if no such line exists.
+func indexOfKeyBlock(text, key string) int {
+	offset := 0
+	for _, line := range splitLines([]byte(text)) {
+		if leadingKey(line) == key {
+			return offset - 1
+		}
+		offset += len(line) + 1
+	}
+	return -1
+}
+
+func TestGenerateSampleYAMLWithCommentsAnnotatesByFullPath(t *testing.T) {
+	const doc = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    listKind: WidgetList
+    plural: widgets
+    singular: widget
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              name:
+                type: string
+                description: the widget's display name
+              owner:
+                type: object
+                properties:
+                  name:
+                    type: string
+                    description: the owner's contact name
+`
+	crd := mustDecode(t, doc)
+
+	out, err := GenerateSampleYAML(crd, "v1", SampleOptions{Mode: SampleWithComments})
+	if err != nil {
+		t.Fatalf("GenerateSampleYAML returned error: %v", err)
+	}
+	text := string(out)
+
+	if !strings.Contains(text, "the widget's display name") {
+		t.Errorf("expected spec.name's description in output, got:\n%s", text)
+	}
+	if !strings.Contains(text, "the owner's contact name") {
+		t.Errorf("expected spec.owner.name's description in output, got:\n%s", text)
+	}
+
+	// spec.name and spec.owner.name share the bare key "name" at different
+	// depths (and GenerateSample's own synthesized metadata.name shares it
+	// at yet another depth) — disambiguate by slicing out the "spec:" block
+	// and then the "owner:" sub-block within it, locating each by its
+	// trimmed key (via leadingKey) rather than a literal indented substring,
+	// since the marshalled indentation depth isn't part of this test's
+	// contract.
+	specIdx := indexOfKeyBlock(text, "spec")
+	if specIdx == -1 {
+		t.Fatalf("expected a top-level \"spec:\" block, got:\n%s", text)
+	}
+	specBlock := text[specIdx:]
+
+	ownerIdx := indexOfKeyBlock(specBlock[1:], "owner") + 1
+	if ownerIdx == 0 {
+		t.Fatalf("expected a \"owner:\" block nested under spec, got:\n%s", specBlock)
+	}
+	specOwnBlock, ownerBlock := specBlock[:ownerIdx], specBlock[ownerIdx:]
+
+	if !strings.Contains(specOwnBlock, "the widget's display name") {
+		t.Errorf("spec.name is missing its own description in:\n%s", specOwnBlock)
+	}
+	if strings.Contains(specOwnBlock, "the owner's contact name") {
+		t.Errorf("spec.name wrongly carries owner.name's description in:\n%s", specOwnBlock)
+	}
+	if !strings.Contains(ownerBlock, "the owner's contact name") {
+		t.Errorf("spec.owner.name is missing its own description in:\n%s", ownerBlock)
+	}
+	if strings.Contains(ownerBlock, "the widget's display name") {
+		t.Errorf("spec.owner.name wrongly carries spec.name's description in:\n%s", ownerBlock)
+	}
+}
+
+func TestGenerateSampleYAMLWithCommentsAnnotatesArrayItemFields(t *testing.T) {
+	const doc = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    listKind: WidgetList
+    plural: widgets
+    singular: widget
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              items:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    name:
+                      type: string
+                      description: the item's name
+`
+	crd := mustDecode(t, doc)
+
+	out, err := GenerateSampleYAML(crd, "v1", SampleOptions{Mode: SampleWithComments})
+	if err != nil {
+		t.Fatalf("GenerateSampleYAML returned error: %v", err)
+	}
+	text := string(out)
+
+	if !strings.Contains(text, "- name: example # description: the item's name") {
+		t.Errorf("expected the array item's \"name\" field to carry its description, got:\n%s", text)
+	}
+}
@@ -0,0 +1,90 @@
+package crdcheck
+
+import (
+	"fmt"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	structuraldefaulting "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/defaulting"
+	structuralpruning "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/pruning"
+	apiservervalidation "k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ValidateOptions configures ValidateCR.
+type ValidateOptions struct {
+	// Version selects which entry of spec.versions[].name to validate
+	// against. Defaults to the CRD's storage version when empty.
+	Version string
+}
+
+// ValidateCR converts crd's OpenAPIV3Schema for the selected version into a
+// structural schema, prunes and defaults cr against it, and runs it through
+// the same OpenAPI validator the apiserver uses. This lets a controller
+// pre-validate a CR it is about to create or patch and get the same
+// field.ErrorList an apiserver 422 would have carried, without a round trip
+// to the cluster.
+//
+// The pruned and defaulted object is returned alongside the error list so
+// callers can use it directly, e.g. to build the same request they'd have
+// sent to the apiserver.
+func ValidateCR(crd *apiextensionsv1.CustomResourceDefinition, cr *unstructured.Unstructured, opts ValidateOptions) (*unstructured.Unstructured, field.ErrorList) {
+	version := opts.Version
+	if version == "" {
+		version = storageVersion(crd)
+	}
+
+	v1Validation, err := validationForVersion(crd, version)
+	if err != nil {
+		return cr, field.ErrorList{field.InternalError(field.NewPath(""), err)}
+	}
+
+	// structuralschema.NewStructural and apiservervalidation.NewSchemaValidator
+	// both operate on the internal (unversioned) apiextensions types, not the
+	// v1 types the CRD is stored as, so convert via the generated v1<->internal
+	// conversion functions first.
+	internalValidation := &apiextensions.CustomResourceValidation{}
+	if err := apiextensionsv1.Convert_v1_CustomResourceValidation_To_apiextensions_CustomResourceValidation(v1Validation, internalValidation, nil); err != nil {
+		return cr, field.ErrorList{field.InternalError(field.NewPath(""), fmt.Errorf("crdcheck: converting schema to internal type: %w", err))}
+	}
+
+	structural, err := structuralschema.NewStructural(internalValidation.OpenAPIV3Schema)
+	if err != nil {
+		return cr, field.ErrorList{field.InternalError(field.NewPath(""), fmt.Errorf("crdcheck: building structural schema: %w", err))}
+	}
+
+	out := cr.DeepCopy()
+	structuralpruning.Prune(out.Object, structural, true)
+	structuraldefaulting.Default(out.Object, structural)
+
+	validator, _, err := apiservervalidation.NewSchemaValidator(internalValidation.OpenAPIV3Schema)
+	if err != nil {
+		return out, field.ErrorList{field.InternalError(field.NewPath(""), fmt.Errorf("crdcheck: building schema validator: %w", err))}
+	}
+
+	result := apiservervalidation.ValidateCustomResource(field.NewPath(""), out.Object, validator)
+	return out, result
+}
+
+func validationForVersion(crd *apiextensionsv1.CustomResourceDefinition, version string) (*apiextensionsv1.CustomResourceValidation, error) {
+	for _, v := range crd.Spec.Versions {
+		if v.Name == version && v.Schema != nil {
+			return v.Schema, nil
+		}
+	}
+	return nil, fmt.Errorf("crdcheck: version %q not found in CRD %s", version, crd.Name)
+}
+
+func storageVersion(crd *apiextensionsv1.CustomResourceDefinition) string {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+	if len(crd.Spec.Versions) > 0 {
+		return crd.Spec.Versions[0].Name
+	}
+	return ""
+}
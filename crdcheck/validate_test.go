@@ -0,0 +1,123 @@
+package crdcheck
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestValidateCRPrunesDefaultsAndValidates(t *testing.T) {
+	const doc = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    listKind: WidgetList
+    plural: widgets
+    singular: widget
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            required: ["size"]
+            properties:
+              size:
+                type: integer
+              mode:
+                type: string
+                default: standard
+`
+
+	crd, err := SafeDecodeCRD([]byte(doc))
+	if err != nil {
+		t.Fatalf("SafeDecodeCRD returned error: %v", err)
+	}
+
+	cr := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "example"},
+		"spec": map[string]interface{}{
+			"size":   int64(3),
+			"bogus":  "should be pruned",
+			"nested": map[string]interface{}{"alsoBogus": true},
+		},
+	}}
+
+	out, errs := ValidateCR(crd, cr, ValidateOptions{})
+	if len(errs) != 0 {
+		t.Fatalf("expected no validation errors for a valid CR, got: %v", errs)
+	}
+
+	spec, ok := out.Object["spec"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("out.spec missing or wrong type: %#v", out.Object["spec"])
+	}
+	if _, present := spec["bogus"]; present {
+		t.Errorf("expected unknown field 'bogus' to be pruned, still present: %#v", spec)
+	}
+	if _, present := spec["nested"]; present {
+		t.Errorf("expected unknown field 'nested' to be pruned, still present: %#v", spec)
+	}
+	if mode, _ := spec["mode"].(string); mode != "standard" {
+		t.Errorf("expected default mode 'standard' to be applied, got %q", mode)
+	}
+
+	invalid := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "example"},
+		"spec": map[string]interface{}{
+			"size": "not-an-integer",
+		},
+	}}
+
+	_, errs = ValidateCR(crd, invalid, ValidateOptions{})
+	if len(errs) == 0 {
+		t.Fatal("expected a validation error for a wrong-typed 'size' field, got none")
+	}
+}
+
+func TestValidateCRUnknownVersion(t *testing.T) {
+	crd, err := SafeDecodeCRD([]byte(`
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    listKind: WidgetList
+    plural: widgets
+    singular: widget
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+`))
+	if err != nil {
+		t.Fatalf("SafeDecodeCRD returned error: %v", err)
+	}
+
+	cr := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	_, errs := ValidateCR(crd, cr, ValidateOptions{Version: "v2"})
+	if len(errs) == 0 {
+		t.Fatal("expected an error for a version absent from spec.versions, got none")
+	}
+}
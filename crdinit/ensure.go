@@ -0,0 +1,130 @@
+// Package crdinit embeds the authoritative CRD YAML produced by
+// controller-gen and reconciles it against a cluster, the same way an
+// operator's init flow verifies and upgrades its own CRDs before starting.
+// Embedding the YAML here — instead of hand-maintaining the equivalent
+// JSONSchemaProps literals inline — keeps the "expected" schema used by
+// crdcheck from drifting out of sync with the real controller-gen output.
+package crdinit
+
+import (
+	"context"
+	"embed"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kaovilai/go-playground/crdcheck"
+)
+
+//go:embed crds/*.yaml
+var embeddedCRDs embed.FS
+
+// Options configures EnsureCRDs.
+type Options struct {
+	// Install creates any embedded CRD that is missing from the cluster.
+	Install bool
+	// Upgrade replaces an existing CRD with the embedded, authoritative
+	// version (via Update) when drift is found against it.
+	Upgrade bool
+}
+
+// Client is the subset of a CRD-managing client EnsureCRDs needs. Its method
+// set matches the generated apiextensionsv1 clientset's
+// CustomResourceDefinitionInterface exactly, so that clientset satisfies
+// Client directly with no adapter.
+type Client interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*apiextensionsv1.CustomResourceDefinition, error)
+	Create(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition, opts metav1.CreateOptions) (*apiextensionsv1.CustomResourceDefinition, error)
+	Update(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition, opts metav1.UpdateOptions) (*apiextensionsv1.CustomResourceDefinition, error)
+}
+
+// Action records what EnsureCRDs did, or would need to do, for a single
+// embedded CRD.
+type Action int
+
+const (
+	ActionNone Action = iota
+	ActionCreated
+	ActionUpgraded
+	ActionDriftOnly
+)
+
+// Result is the outcome of reconciling a single embedded CRD.
+type Result struct {
+	Name   string
+	Action Action
+	Report *crdcheck.DriftReport
+}
+
+// EnsureCRDs loads every embedded CRD YAML, compares it against the
+// in-cluster version using crdcheck.Diff, and creates or updates it
+// according to opts. When neither opts.Install nor opts.Upgrade is set,
+// EnsureCRDs only reports drift and never mutates the cluster.
+func EnsureCRDs(ctx context.Context, client Client, opts Options) ([]Result, error) {
+	entries, err := embeddedCRDs.ReadDir("crds")
+	if err != nil {
+		return nil, fmt.Errorf("crdinit: reading embedded crds: %w", err)
+	}
+
+	var results []Result
+	for _, entry := range entries {
+		expected, err := loadEmbedded(entry.Name())
+		if err != nil {
+			return results, fmt.Errorf("crdinit: loading %s: %w", entry.Name(), err)
+		}
+
+		result, err := ensureOne(ctx, client, expected, opts)
+		if err != nil {
+			return results, fmt.Errorf("crdinit: reconciling %s: %w", expected.Name, err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func ensureOne(ctx context.Context, client Client, expected *apiextensionsv1.CustomResourceDefinition, opts Options) (Result, error) {
+	actual, err := client.Get(ctx, expected.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return Result{}, fmt.Errorf("getting CRD %q: %w", expected.Name, err)
+		}
+		if !opts.Install {
+			return Result{Name: expected.Name, Action: ActionNone}, nil
+		}
+		if _, createErr := client.Create(ctx, expected, metav1.CreateOptions{}); createErr != nil {
+			return Result{}, createErr
+		}
+		return Result{Name: expected.Name, Action: ActionCreated}, nil
+	}
+
+	report := crdcheck.Diff(expected, actual)
+	if !report.HasDrift() {
+		return Result{Name: expected.Name, Action: ActionNone, Report: report}, nil
+	}
+	if !opts.Upgrade {
+		return Result{Name: expected.Name, Action: ActionDriftOnly, Report: report}, nil
+	}
+
+	expected.ResourceVersion = actual.ResourceVersion
+	if _, err := client.Update(ctx, expected, metav1.UpdateOptions{}); err != nil {
+		return Result{}, err
+	}
+	return Result{Name: expected.Name, Action: ActionUpgraded, Report: report}, nil
+}
+
+func loadEmbedded(fileName string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	raw, err := embeddedCRDs.ReadFile("crds/" + fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	crd, err := crdcheck.SafeDecodeCRD(raw)
+	if err != nil {
+		return nil, fmt.Errorf("decoding embedded CRD: %w", err)
+	}
+
+	return crd, nil
+}
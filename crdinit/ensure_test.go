@@ -0,0 +1,145 @@
+package crdinit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeClient is an in-memory Client used to exercise EnsureCRDs without a
+// real cluster. getErr, when set, is returned verbatim from Get instead of
+// a NotFound error, to exercise transient-error handling.
+type fakeClient struct {
+	crds   map[string]*apiextensionsv1.CustomResourceDefinition
+	getErr error
+}
+
+func (f *fakeClient) Get(ctx context.Context, name string, opts metav1.GetOptions) (*apiextensionsv1.CustomResourceDefinition, error) {
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	crd, ok := f.crds[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(apiextensionsv1.Resource("customresourcedefinitions"), name)
+	}
+	return crd, nil
+}
+
+func (f *fakeClient) Create(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition, opts metav1.CreateOptions) (*apiextensionsv1.CustomResourceDefinition, error) {
+	if f.crds == nil {
+		f.crds = map[string]*apiextensionsv1.CustomResourceDefinition{}
+	}
+	f.crds[crd.Name] = crd
+	return crd, nil
+}
+
+func (f *fakeClient) Update(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition, opts metav1.UpdateOptions) (*apiextensionsv1.CustomResourceDefinition, error) {
+	f.crds[crd.Name] = crd
+	return crd, nil
+}
+
+var _ Client = (*fakeClient)(nil)
+
+func TestEnsureCRDsInstallsMissingCRD(t *testing.T) {
+	client := &fakeClient{crds: map[string]*apiextensionsv1.CustomResourceDefinition{}}
+
+	results, err := EnsureCRDs(context.Background(), client, Options{Install: true})
+	if err != nil {
+		t.Fatalf("EnsureCRDs returned error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("len(results) = %d, want 1", len(results))
+	}
+	if results[0].Action != ActionCreated {
+		t.Errorf("Action = %v, want ActionCreated", results[0].Action)
+	}
+	if _, ok := client.crds[results[0].Name]; !ok {
+		t.Errorf("expected CRD %q to have been created on the client", results[0].Name)
+	}
+}
+
+func TestEnsureCRDsWithoutInstallLeavesMissingCRDAlone(t *testing.T) {
+	client := &fakeClient{crds: map[string]*apiextensionsv1.CustomResourceDefinition{}}
+
+	results, err := EnsureCRDs(context.Background(), client, Options{})
+	if err != nil {
+		t.Fatalf("EnsureCRDs returned error: %v", err)
+	}
+	if results[0].Action != ActionNone {
+		t.Errorf("Action = %v, want ActionNone", results[0].Action)
+	}
+	if len(client.crds) != 0 {
+		t.Errorf("expected no CRD to be created when Install is false, got: %v", client.crds)
+	}
+}
+
+func TestEnsureCRDsReportsDriftWithoutUpgrade(t *testing.T) {
+	embedded, err := loadEmbedded("mycustomresources.example.com.yaml")
+	if err != nil {
+		t.Fatalf("loadEmbedded returned error: %v", err)
+	}
+
+	drifted := embedded.DeepCopy()
+	specSchema := drifted.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+	delete(specSchema.Properties, "bar")
+	drifted.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"] = specSchema
+
+	client := &fakeClient{crds: map[string]*apiextensionsv1.CustomResourceDefinition{drifted.Name: drifted}}
+
+	results, err := EnsureCRDs(context.Background(), client, Options{})
+	if err != nil {
+		t.Fatalf("EnsureCRDs returned error: %v", err)
+	}
+	if results[0].Action != ActionDriftOnly {
+		t.Errorf("Action = %v, want ActionDriftOnly", results[0].Action)
+	}
+	if results[0].Report == nil || !results[0].Report.HasDrift() {
+		t.Errorf("expected a drift report, got: %+v", results[0].Report)
+	}
+	if client.crds[drifted.Name].Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["bar"].Type != "" {
+		t.Error("expected the cluster CRD to be left untouched when Upgrade is false")
+	}
+}
+
+func TestEnsureCRDsUpgradesOnDrift(t *testing.T) {
+	embedded, err := loadEmbedded("mycustomresources.example.com.yaml")
+	if err != nil {
+		t.Fatalf("loadEmbedded returned error: %v", err)
+	}
+
+	drifted := embedded.DeepCopy()
+	specSchema := drifted.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+	delete(specSchema.Properties, "bar")
+	drifted.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"] = specSchema
+	drifted.ResourceVersion = "1"
+
+	client := &fakeClient{crds: map[string]*apiextensionsv1.CustomResourceDefinition{drifted.Name: drifted}}
+
+	results, err := EnsureCRDs(context.Background(), client, Options{Upgrade: true})
+	if err != nil {
+		t.Fatalf("EnsureCRDs returned error: %v", err)
+	}
+	if results[0].Action != ActionUpgraded {
+		t.Errorf("Action = %v, want ActionUpgraded", results[0].Action)
+	}
+	if _, ok := client.crds[drifted.Name].Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["bar"]; !ok {
+		t.Error("expected the cluster CRD to be upgraded to include 'bar' after Upgrade")
+	}
+}
+
+func TestEnsureCRDsPropagatesTransientGetError(t *testing.T) {
+	wantErr := errors.New("apiserver unavailable")
+	client := &fakeClient{getErr: wantErr}
+
+	_, err := EnsureCRDs(context.Background(), client, Options{})
+	if err == nil {
+		t.Fatal("expected EnsureCRDs to propagate a non-NotFound Get error, got nil")
+	}
+	if !errors.Is(err, wantErr) {
+		t.Errorf("EnsureCRDs error = %v, want it to wrap %v", err, wantErr)
+	}
+}
@@ -0,0 +1,145 @@
+// Package crdwait polls a CustomResourceDefinition until it is safe for a
+// controller to start informers against it: the apiserver has accepted its
+// names and marked it Established, and — when the CRD declares a conversion
+// webhook — the webhook itself is ready to serve conversion requests.
+package crdwait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Getter fetches the current state of a CRD by name. Its method set matches
+// the generated apiextensionsv1 clientset's CustomResourceDefinitionInterface
+// exactly, so that clientset satisfies Getter directly with no adapter.
+type Getter interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*apiextensionsv1.CustomResourceDefinition, error)
+}
+
+// WebhookProber probes a conversion webhook's readiness once its CA bundle
+// has been populated. Implementations typically dial the webhook's service
+// and send a no-op ConversionReview.
+type WebhookProber interface {
+	Probe(ctx context.Context, webhook *apiextensionsv1.WebhookConversion) error
+}
+
+// NamesConflictError is returned when the CRD's NamesAccepted condition is
+// False because one of its names conflicts with another CRD (the condition's
+// reason, e.g. KindConflict, is kept in Reason), so callers can decide
+// whether to retry, rename, or abort instead of treating it as a generic
+// timeout.
+type NamesConflictError struct {
+	Name    string
+	Reason  string
+	Message string
+}
+
+func (e *NamesConflictError) Error() string {
+	return fmt.Sprintf("crdwait: %s: names not accepted (%s): %s", e.Name, e.Reason, e.Message)
+}
+
+// TimeoutError is returned when timeout elapses before the CRD (and its
+// conversion webhook, if any) became ready.
+type TimeoutError struct {
+	Name   string
+	Waited time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("crdwait: %s: not ready after %s", e.Name, e.Waited)
+}
+
+const pollInterval = 500 * time.Millisecond
+
+// WaitForCRDReady polls name until its Established and NamesAccepted
+// conditions are both True, and — if it declares a conversion webhook —
+// until the webhook's CA bundle is populated and prober.Probe succeeds
+// against it. It returns a *NamesConflictError if the apiserver rejected
+// the CRD's names, or a *TimeoutError if timeout elapses first.
+func WaitForCRDReady(ctx context.Context, getter Getter, prober WebhookProber, name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	for {
+		crd, err := getter.Get(ctx, name, metav1.GetOptions{})
+		if err == nil {
+			ready, conflictErr := evaluateConditions(crd)
+			if conflictErr != nil {
+				return conflictErr
+			}
+			if ready {
+				if webhookErr := waitForConversionWebhook(ctx, prober, crd); webhookErr != nil {
+					if errors.Is(webhookErr, context.DeadlineExceeded) {
+						return &TimeoutError{Name: name, Waited: time.Since(start)}
+					}
+					return webhookErr
+				}
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return &TimeoutError{Name: name, Waited: time.Since(start)}
+		case <-ticker.C:
+		}
+	}
+}
+
+// evaluateConditions inspects a CRD's status conditions, returning whether
+// it is fully established and, separately, a typed error if its names were
+// rejected outright.
+func evaluateConditions(crd *apiextensionsv1.CustomResourceDefinition) (ready bool, err error) {
+	var established, namesAccepted bool
+	for _, cond := range crd.Status.Conditions {
+		switch cond.Type {
+		case apiextensionsv1.Established:
+			established = cond.Status == apiextensionsv1.ConditionTrue
+		case apiextensionsv1.NamesAccepted:
+			namesAccepted = cond.Status == apiextensionsv1.ConditionTrue
+			if cond.Status == apiextensionsv1.ConditionFalse {
+				return false, &NamesConflictError{Name: crd.Name, Reason: cond.Reason, Message: cond.Message}
+			}
+		}
+	}
+	return established && namesAccepted, nil
+}
+
+func waitForConversionWebhook(ctx context.Context, prober WebhookProber, crd *apiextensionsv1.CustomResourceDefinition) error {
+	if crd.Spec.Conversion == nil || crd.Spec.Conversion.Strategy != apiextensionsv1.WebhookConverter {
+		return nil
+	}
+	webhook := crd.Spec.Conversion.Webhook
+	if webhook == nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if webhook.ClientConfig != nil && len(webhook.ClientConfig.CABundle) > 0 {
+			if prober == nil {
+				return nil
+			}
+			if err := prober.Probe(ctx, webhook); err == nil {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package crdwait
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeGetter struct {
+	crd *apiextensionsv1.CustomResourceDefinition
+	err error
+}
+
+func (f *fakeGetter) Get(ctx context.Context, name string, opts metav1.GetOptions) (*apiextensionsv1.CustomResourceDefinition, error) {
+	return f.crd, f.err
+}
+
+var _ Getter = (*fakeGetter)(nil)
+
+func establishedCRD(name string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{
+			Conditions: []apiextensionsv1.CustomResourceDefinitionCondition{
+				{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionTrue},
+				{Type: apiextensionsv1.NamesAccepted, Status: apiextensionsv1.ConditionTrue},
+			},
+		},
+	}
+}
+
+func TestWaitForCRDReadySucceedsWithoutWebhook(t *testing.T) {
+	getter := &fakeGetter{crd: establishedCRD("widgets.example.com")}
+
+	err := WaitForCRDReady(context.Background(), getter, nil, "widgets.example.com", time.Second)
+	if err != nil {
+		t.Fatalf("WaitForCRDReady returned error: %v", err)
+	}
+}
+
+func TestWaitForCRDReadyReturnsNamesConflictError(t *testing.T) {
+	crd := establishedCRD("widgets.example.com")
+	crd.Status.Conditions = []apiextensionsv1.CustomResourceDefinitionCondition{
+		{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionFalse},
+		{
+			Type:    apiextensionsv1.NamesAccepted,
+			Status:  apiextensionsv1.ConditionFalse,
+			Reason:  "NamingConflict",
+			Message: "kind Widget already in use",
+		},
+	}
+	getter := &fakeGetter{crd: crd}
+
+	err := WaitForCRDReady(context.Background(), getter, nil, "widgets.example.com", time.Second)
+
+	var conflictErr *NamesConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("WaitForCRDReady error = %v, want *NamesConflictError", err)
+	}
+	if conflictErr.Reason != "NamingConflict" {
+		t.Errorf("conflictErr.Reason = %q, want %q", conflictErr.Reason, "NamingConflict")
+	}
+}
+
+func TestWaitForCRDReadyTimesOutWhenNeverEstablished(t *testing.T) {
+	crd := establishedCRD("widgets.example.com")
+	crd.Status.Conditions = []apiextensionsv1.CustomResourceDefinitionCondition{
+		{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionFalse},
+		{Type: apiextensionsv1.NamesAccepted, Status: apiextensionsv1.ConditionTrue},
+	}
+	getter := &fakeGetter{crd: crd}
+
+	err := WaitForCRDReady(context.Background(), getter, nil, "widgets.example.com", 50*time.Millisecond)
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("WaitForCRDReady error = %v, want *TimeoutError", err)
+	}
+}
+
+type fakeProber struct {
+	calls int
+	err   error
+}
+
+func (f *fakeProber) Probe(ctx context.Context, webhook *apiextensionsv1.WebhookConversion) error {
+	f.calls++
+	return f.err
+}
+
+func withConversionWebhook(crd *apiextensionsv1.CustomResourceDefinition, caBundle []byte) *apiextensionsv1.CustomResourceDefinition {
+	crd.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{
+			ClientConfig: &apiextensionsv1.WebhookClientConfig{CABundle: caBundle},
+		},
+	}
+	return crd
+}
+
+func TestWaitForCRDReadyWaitsForWebhookCABundleThenProbes(t *testing.T) {
+	crd := withConversionWebhook(establishedCRD("widgets.example.com"), []byte("ca-bundle"))
+	getter := &fakeGetter{crd: crd}
+	prober := &fakeProber{}
+
+	err := WaitForCRDReady(context.Background(), getter, prober, "widgets.example.com", time.Second)
+	if err != nil {
+		t.Fatalf("WaitForCRDReady returned error: %v", err)
+	}
+	if prober.calls == 0 {
+		t.Error("expected the webhook prober to be called at least once")
+	}
+}
+
+func TestWaitForCRDReadyTimesOutWhenCABundleNeverPopulated(t *testing.T) {
+	crd := withConversionWebhook(establishedCRD("widgets.example.com"), nil)
+	getter := &fakeGetter{crd: crd}
+	prober := &fakeProber{}
+
+	err := WaitForCRDReady(context.Background(), getter, prober, "widgets.example.com", 50*time.Millisecond)
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("WaitForCRDReady error = %v, want *TimeoutError", err)
+	}
+	if prober.calls != 0 {
+		t.Errorf("expected the prober never to be called while CABundle is empty, got %d calls", prober.calls)
+	}
+}
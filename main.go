@@ -1,23 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime"
-	"sigs.k8s.io/yaml"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"kaovilai/go-playground/crdcheck"
+	"kaovilai/go-playground/crdinit"
 )
 
-// mockOutOfDateCRDFromCluster represents an out-of-date CustomResourceDefinition.
+// mockOutOfDateCRDFromCluster represents an out-of-date CustomResourceDefinition
+// as it might be found in a cluster whose CRD has drifted from crdinit's
+// embedded, authoritative version (crdinit/crds/mycustomresources.example.com.yaml).
 // - The 'spec' properties are missing 'bar'.
 // - The 'status' properties are missing 'ready'.
 const mockOutOfDateCRDFromCluster = `
 apiVersion: apiextensions.k8s.io/v1
 kind: CustomResourceDefinition
 metadata:
-  name: mycustomresources.example.com-out-of-date
+  name: mycustomresources.example.com
 spec:
   group: example.com
   names:
@@ -46,13 +51,13 @@ spec:
     storage: true
 `
 
-// mockUpToDateCRDFromCluster represents a fully up-to-date CustomResourceDefinition.
-// It contains all the fields the Go controller expects.
+// mockUpToDateCRDFromCluster represents a cluster CRD that exactly matches
+// crdinit's embedded, authoritative version.
 const mockUpToDateCRDFromCluster = `
 apiVersion: apiextensions.k8s.io/v1
 kind: CustomResourceDefinition
 metadata:
-  name: mycustomresources.example.com-up-to-date
+  name: mycustomresources.example.com
 spec:
   group: example.com
   names:
@@ -86,92 +91,57 @@ spec:
     storage: true
 `
 
-// compareSchemaProperties checks if all property keys from `expected` exist in `actual`.
-func compareSchemaProperties(actual, expected map[string]apiextensionsv1.JSONSchemaProps) bool {
-	allPropertiesFound := true
-	for key := range expected {
-		if _, ok := actual[key]; !ok {
-			fmt.Printf("  FAIL: Expected property '%s' not found in CRD schema.\n", key)
-			allPropertiesFound = false
-		} else {
-			fmt.Printf("  OK: Property '%s' found in CRD schema.\n", key)
-		}
-	}
-	return allPropertiesFound
+// fakeCRDClient is an in-memory stand-in for an apiextensionsv1 clientset's
+// CustomResourceDefinitionInterface, so this demo can exercise
+// crdinit.EnsureCRDs without a real cluster.
+type fakeCRDClient struct {
+	crds map[string]*apiextensionsv1.CustomResourceDefinition
 }
 
-// checkCRD runs the validation logic against a given CRD YAML string.
-func checkCRD(crdName, crdYaml string) {
-	log.Printf("===== Running Check for: %s =====\n", crdName)
-
-	// 1. Unmarshal the raw CRD YAML into an unstructured object.
-	var unstructuredObj unstructured.Unstructured
-	err := yaml.Unmarshal([]byte(crdYaml), &unstructuredObj.Object)
-	if err != nil {
-		log.Fatalf("Failed to unmarshal mock CRD yaml for %s: %v", crdName, err)
+func (c *fakeCRDClient) Get(ctx context.Context, name string, opts metav1.GetOptions) (*apiextensionsv1.CustomResourceDefinition, error) {
+	crd, ok := c.crds[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(apiextensionsv1.Resource("customresourcedefinitions"), name)
 	}
+	return crd, nil
+}
 
-	// 2. Convert the unstructured object into a strongly-typed CRD struct.
-	var crd apiextensionsv1.CustomResourceDefinition
-	err = runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredObj.Object, &crd)
-	if err != nil {
-		log.Fatalf("Failed to convert unstructured object for %s: %v", crdName, err)
-	}
-	log.Println("Successfully converted unstructured object to typed CRD.")
+func (c *fakeCRDClient) Create(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition, opts metav1.CreateOptions) (*apiextensionsv1.CustomResourceDefinition, error) {
+	c.crds[crd.Name] = crd
+	return crd, nil
+}
 
-	// 3. Define the "expected" or "up-to-date" schema properties that the controller requires.
-	expectedSpecProperties := map[string]apiextensionsv1.JSONSchemaProps{
-		"foo": {Type: "string"},
-		"bar": {Type: "integer", Format: "int64"},
-	}
-	expectedStatusProperties := map[string]apiextensionsv1.JSONSchemaProps{
-		"message": {Type: "string"},
-		"ready":   {Type: "boolean"},
-	}
+func (c *fakeCRDClient) Update(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition, opts metav1.UpdateOptions) (*apiextensionsv1.CustomResourceDefinition, error) {
+	c.crds[crd.Name] = crd
+	return crd, nil
+}
 
-	// 4. Find the v1 schema and its properties from the parsed CRD.
-	var v1schema *apiextensionsv1.CustomResourceValidation
-	for _, version := range crd.Spec.Versions {
-		if version.Name == "v1" {
-			v1schema = version.Schema
-			break
-		}
-	}
+// checkCRD runs crdinit.EnsureCRDs against a cluster that only contains
+// clusterCRDYaml, reporting whether it matches crdinit's embedded,
+// authoritative CRD.
+func checkCRD(crdName, clusterCRDYaml string) {
+	log.Printf("===== Running Check for: %s =====\n", crdName)
 
-	if v1schema == nil || v1schema.OpenAPIV3Schema == nil {
-		log.Printf("Could not find v1 schema in the CRD: %s", crdName)
-		return
+	crd, err := crdcheck.SafeDecodeCRD([]byte(clusterCRDYaml))
+	if err != nil {
+		log.Fatalf("Failed to decode mock CRD yaml for %s: %v", crdName, err)
 	}
 
-	actualCRDSchema := v1schema.OpenAPIV3Schema
+	client := &fakeCRDClient{crds: map[string]*apiextensionsv1.CustomResourceDefinition{crd.Name: crd}}
 
-	// 5. Compare the properties to see if the CRD from the cluster is "up-to-date".
-	log.Println("\n--- Checking if CRD Spec schema is up-to-date ---")
-	specSchema, ok := actualCRDSchema.Properties["spec"]
-	if !ok {
-		log.Printf("CRD schema for %s is missing 'spec' property.", crdName)
-		return
+	results, err := crdinit.EnsureCRDs(context.Background(), client, crdinit.Options{})
+	if err != nil {
+		log.Fatalf("EnsureCRDs failed for %s: %v", crdName, err)
 	}
-	specUpToDate := compareSchemaProperties(specSchema.Properties, expectedSpecProperties)
 
-	log.Println("\n--- Checking if CRD Status schema is up-to-date ---")
-	statusSchema, ok := actualCRDSchema.Properties["status"]
-	if !ok {
-		log.Printf("CRD schema for %s is missing 'status' property.", crdName)
-		return
-	}
-	statusUpToDate := compareSchemaProperties(statusSchema.Properties, expectedStatusProperties)
-
-	fmt.Println("\n--- Check complete ---")
-	if specUpToDate && statusUpToDate {
-		log.Println("Conclusion: The installed CustomResourceDefinition schema appears to be up-to-date.")
-	} else {
-		log.Println("Conclusion: The installed CustomResourceDefinition schema is out-of-date.")
-		if !specUpToDate {
-			log.Println("-> 'spec' properties are missing.")
+	for _, result := range results {
+		if result.Report == nil || !result.Report.HasDrift() {
+			log.Printf("Conclusion: %q is up-to-date.", result.Name)
+			continue
 		}
-		if !statusUpToDate {
-			log.Println("-> 'status' properties are missing.")
+		log.Printf("Conclusion: %q is out-of-date (worst severity: %s):", result.Name, result.Report.WorstSeverity())
+		for _, f := range result.Report.Findings {
+			log.Printf("  [%s] %s: %s", f.Severity, f.Path, f.Message)
 		}
 	}
 	fmt.Println() // Add a blank line for better separation
@@ -186,4 +156,3 @@ func main() {
 	// Run the check for the up-to-date CRD
 	checkCRD("Up-to-Date CRD", mockUpToDateCRDFromCluster)
 }
-